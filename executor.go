@@ -0,0 +1,360 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// Executor walks a parsed Node tree and runs it, propagating exit codes so
+// "&&"/"||" can short-circuit and ";" can chain unconditionally.
+type Executor struct{}
+
+// NewExecutor returns a ready-to-use Executor.
+func NewExecutor() *Executor {
+	return &Executor{}
+}
+
+// Run executes node and returns the exit code of the last command run
+// along with any error from that last command.
+func (e *Executor) Run(node Node) (int, error) {
+	switch n := node.(type) {
+	case *Pipeline:
+		return e.runPipeline(n, false)
+	case *Background:
+		return e.runBackgroundNode(n)
+	case *Sequence:
+		var code int
+		var err error
+		for _, sub := range n.nodes {
+			code, err = e.Run(sub)
+			if err != nil {
+				fmt.Println(err)
+			}
+		}
+		return code, nil
+	case *AndList:
+		code, err := e.Run(n.left)
+		if err != nil {
+			fmt.Println(err)
+		}
+		if code != 0 {
+			return code, nil
+		}
+		return e.Run(n.right)
+	case *OrList:
+		code, err := e.Run(n.left)
+		if err != nil {
+			fmt.Println(err)
+		}
+		if code == 0 {
+			return code, nil
+		}
+		return e.Run(n.right)
+	default:
+		return 1, fmt.Errorf("unknown node type %T", node)
+	}
+}
+
+// runBackgroundNode backgrounds inner if it is a Pipeline. Backgrounding a
+// compound node (e.g. "a && b &") isn't supported yet.
+func (e *Executor) runBackgroundNode(n *Background) (int, error) {
+	p, ok := n.inner.(*Pipeline)
+	if !ok {
+		return 1, fmt.Errorf("cannot background a compound command")
+	}
+	return e.runPipeline(p, true)
+}
+
+// exitCodeOf extracts the numeric exit code of a finished *exec.Cmd. A
+// process killed by a signal reports 128+signal, matching shell convention;
+// an error that never produced a process exit status falls back to 1.
+func exitCodeOf(cmd *exec.Cmd, err error) int {
+	if cmd.ProcessState != nil {
+		if status, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return 128 + int(status.Signal())
+		}
+		return cmd.ProcessState.ExitCode()
+	}
+	if err != nil {
+		return 1
+	}
+	return 0
+}
+
+// applyRedirs opens the files named by redirs and wires them onto cmd's
+// Stdin/Stdout/Stderr, honoring fd-duplication redirections like "2>&1".
+// The caller must close the returned files once the command has finished.
+func applyRedirs(cmd *exec.Cmd, redirs []Redir) ([]io.Closer, error) {
+	var closers []io.Closer
+	var dupDst, dupSrc []int
+
+	for _, r := range redirs {
+		if r.target == "" && r.dupFd >= 0 {
+			dupDst = append(dupDst, r.fd)
+			dupSrc = append(dupSrc, r.dupFd)
+			continue
+		}
+
+		var f *os.File
+		var err error
+		switch {
+		case r.fd == 0:
+			f, err = os.Open(r.target)
+		case r.append:
+			f, err = os.OpenFile(r.target, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		default:
+			f, err = os.OpenFile(r.target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		}
+		if err != nil {
+			for _, c := range closers {
+				c.Close()
+			}
+			return nil, err
+		}
+		closers = append(closers, f)
+
+		switch r.fd {
+		case 0:
+			cmd.Stdin = f
+		case 1:
+			cmd.Stdout = f
+		case 2:
+			cmd.Stderr = f
+		}
+	}
+
+	for i, dst := range dupDst {
+		src := dupSrc[i]
+		switch {
+		case dst == 2 && src == 1:
+			cmd.Stderr = cmd.Stdout
+		case dst == 1 && src == 2:
+			cmd.Stdout = cmd.Stderr
+		}
+	}
+
+	return closers, nil
+}
+
+// buildCmds turns a pipeline's commands into unstarted *exec.Cmd values,
+// wiring inter-command pipes and per-command redirections. The caller
+// still needs to start each command (in order, so process-group
+// assignment can reference the first PID) and close the returned files
+// once the pipeline finishes.
+func buildCmds(cmds []Command) ([]*exec.Cmd, []io.Closer, error) {
+	var execCmds []*exec.Cmd
+	var closers []io.Closer
+
+	for _, command := range cmds {
+		cmd := exec.Command(command.name, command.args...)
+		cs, err := applyRedirs(cmd, command.redirs)
+		closers = append(closers, cs...)
+		if err != nil {
+			return nil, closers, err
+		}
+		execCmds = append(execCmds, cmd)
+	}
+
+	for i := 0; i < len(execCmds)-1; i++ {
+		if execCmds[i].Stdout != nil {
+			continue
+		}
+		stdout, err := execCmds[i].StdoutPipe()
+		if err != nil {
+			return nil, closers, err
+		}
+		execCmds[i+1].Stdin = stdout
+	}
+
+	if execCmds[0].Stdin == nil {
+		execCmds[0].Stdin = os.Stdin
+	}
+	if execCmds[len(execCmds)-1].Stdout == nil {
+		execCmds[len(execCmds)-1].Stdout = os.Stdout
+	}
+	for _, cmd := range execCmds {
+		if cmd.Stderr == nil {
+			cmd.Stderr = os.Stderr
+		}
+	}
+
+	return execCmds, closers, nil
+}
+
+// startGroup starts every command in its own new process group (the first
+// command's PID becomes the group's PGID), so Ctrl-C and job-control
+// signals can target the whole pipeline without hitting the shell itself.
+func startGroup(execCmds []*exec.Cmd) (pgid int, err error) {
+	for i, cmd := range execCmds {
+		if i == 0 {
+			cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		} else {
+			cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true, Pgid: pgid}
+		}
+		if err := cmd.Start(); err != nil {
+			return 0, err
+		}
+		if i == 0 {
+			pgid = cmd.Process.Pid
+		}
+	}
+	return pgid, nil
+}
+
+// executeCdCommand executes the "cd" built-in command to change directories.
+func executeCdCommand(args []string) error {
+	var path string
+	if len(args) == 0 { // if no path is defined it defaults to $HOME
+		path = os.Getenv("HOME")
+	} else {
+		path = args[0]
+	}
+	return os.Chdir(path)
+}
+
+// runSingleCommand executes a single command and reports its exit code.
+// Built-in commands cannot be part of pipes.
+func runSingleCommand(command Command) (int, error) {
+	switch command.name {
+	case "":
+		return 0, nil
+	case "exit":
+		os.Exit(0)
+		return 0, nil
+	case "cd":
+		if err := executeCdCommand(command.args); err != nil {
+			return 1, err
+		}
+		return 0, nil
+	case "history":
+		return executeHistoryCommand(historyStore, command.args)
+	case "jobs":
+		return executeJobsCommand()
+	case "fg":
+		return executeFgCommand(command.args)
+	case "bg":
+		return executeBgCommand(command.args)
+	case "kill":
+		return executeKillCommand(command.args)
+	default:
+		return runForegroundPipeline([]Command{command})
+	}
+}
+
+// runPipeline executes commands either in the foreground, waiting for the
+// result, or - when background is true - in a new process group that runs
+// independently while the prompt returns immediately.
+func (e *Executor) runPipeline(p *Pipeline, background bool) (int, error) {
+	if !background && len(p.cmds) == 1 {
+		return runSingleCommand(p.cmds[0])
+	}
+
+	for _, c := range p.cmds {
+		if c.name == "cd" || c.name == "exit" || c.name == "jobs" || c.name == "fg" || c.name == "bg" || c.name == "kill" {
+			return 1, fmt.Errorf("cannot use built-in command '%s' in pipeline", c.name)
+		}
+	}
+
+	if background {
+		return runBackgroundPipeline(p.cmds)
+	}
+	return runForegroundPipeline(p.cmds)
+}
+
+// runForegroundPipeline is the shared path for both a single external
+// command and a multi-stage foreground pipeline: build, start in a fresh
+// process group, forward Ctrl-C to that group while it runs, and wait.
+func runForegroundPipeline(cmds []Command) (int, error) {
+	execCmds, closers, err := buildCmds(cmds)
+	defer func() {
+		for _, c := range closers {
+			c.Close()
+		}
+	}()
+	if err != nil {
+		return 1, err
+	}
+
+	pgid, err := startGroup(execCmds)
+	if err != nil {
+		return 1, err
+	}
+
+	setForegroundPGID(pgid)
+	defer setForegroundPGID(0)
+
+	// Hand the controlling terminal to the new group, the same way
+	// executeFgCommand does for a resumed background job. Without this the
+	// terminal's foreground pgid stays the shell's own, so the first read
+	// from the tty (cat, less, vim, a "read" builtin, ...) earns the child
+	// a SIGTTIN and it stops instead of exiting, hanging cmd.Wait() forever.
+	if err := tcSetForeground(pgid); err == nil {
+		defer tcSetForeground(shellPGID)
+	}
+
+	lastIdx := len(execCmds) - 1
+	var lastErr error
+	for i, cmd := range execCmds {
+		err := cmd.Wait()
+		if i == lastIdx {
+			lastErr = err
+		}
+	}
+
+	return exitCodeOf(execCmds[lastIdx], lastErr), lastErr
+}
+
+// runBackgroundPipeline starts cmds in their own process group and returns
+// immediately; a goroutine waits on the pipeline and reports it through
+// the job table once it finishes.
+func runBackgroundPipeline(cmds []Command) (int, error) {
+	execCmds, closers, err := buildCmds(cmds)
+	if err != nil {
+		for _, c := range closers {
+			c.Close()
+		}
+		return 1, err
+	}
+
+	pgid, err := startGroup(execCmds)
+	if err != nil {
+		for _, c := range closers {
+			c.Close()
+		}
+		return 1, err
+	}
+
+	job := jobTable.Add(execCmds, pgid, commandLine(cmds))
+	fmt.Printf("[%d] %d\n", job.ID, pgid)
+
+	go func() {
+		lastIdx := len(execCmds) - 1
+		var lastErr error
+		for i, cmd := range execCmds {
+			err := cmd.Wait()
+			if i == lastIdx {
+				lastErr = err
+			}
+		}
+		for _, c := range closers {
+			c.Close()
+		}
+		jobTable.Finish(job.ID, exitCodeOf(execCmds[lastIdx], lastErr))
+	}()
+
+	return 0, nil
+}
+
+// commandLine reconstructs a human-readable command line for job listings
+// and "Done" notifications.
+func commandLine(cmds []Command) string {
+	parts := make([]string, 0, len(cmds))
+	for _, c := range cmds {
+		parts = append(parts, strings.TrimSpace(strings.Join(append([]string{c.name}, c.args...), " ")))
+	}
+	return strings.Join(parts, " | ")
+}
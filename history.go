@@ -0,0 +1,195 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/peterh/liner"
+	_ "modernc.org/sqlite"
+)
+
+// seedLinerHistoryLimit bounds how many past commands get backfilled into
+// liner's in-memory ring, so Ctrl-R has more than just the current session
+// to search without loading the entire database into memory.
+const seedLinerHistoryLimit = 200
+
+var history_db_file = os.Getenv("HOME") + "/.gocsh_history.db"
+
+const ansiRed = "\033[31m"
+const ansiReset = "\033[0m"
+
+// HistoryEntry is a single recorded command along with the metadata needed
+// to filter and replay it later.
+type HistoryEntry struct {
+	ID         int64
+	Cmd        string
+	Ts         int64
+	Cwd        string
+	ExitCode   int
+	DurationMs int64
+}
+
+// HistoryStore persists command history to a SQLite database so it can be
+// filtered by day, working directory, or exit status without re-parsing a
+// flat text file.
+type HistoryStore struct {
+	db *sql.DB
+}
+
+// openHistoryStore opens (creating if necessary) the SQLite history database
+// at path and ensures the schema exists.
+func openHistoryStore(path string) (*HistoryStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+
+	const schema = `
+		CREATE TABLE IF NOT EXISTS history (
+			id INTEGER PRIMARY KEY,
+			cmd TEXT,
+			ts INTEGER,
+			cwd TEXT,
+			exit_code INTEGER,
+			duration_ms INTEGER
+		)`
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &HistoryStore{db: db}, nil
+}
+
+// Close releases the underlying database connection.
+func (h *HistoryStore) Close() error {
+	return h.db.Close()
+}
+
+// Insert records one executed command and its outcome.
+func (h *HistoryStore) Insert(entry HistoryEntry) error {
+	_, err := h.db.Exec(
+		`INSERT INTO history (cmd, ts, cwd, exit_code, duration_ms) VALUES (?, ?, ?, ?, ?)`,
+		entry.Cmd, entry.Ts, entry.Cwd, entry.ExitCode, entry.DurationMs,
+	)
+	return err
+}
+
+// query runs a SELECT against the history table and scans the rows into
+// HistoryEntry values.
+func (h *HistoryStore) query(where string, args ...any) ([]HistoryEntry, error) {
+	rows, err := h.db.Query(
+		`SELECT id, cmd, ts, cwd, exit_code, duration_ms FROM history `+where, args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []HistoryEntry
+	for rows.Next() {
+		var e HistoryEntry
+		if err := rows.Scan(&e.ID, &e.Cmd, &e.Ts, &e.Cwd, &e.ExitCode, &e.DurationMs); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// Recent returns the most recently run commands, newest first.
+func (h *HistoryStore) Recent(limit int) ([]HistoryEntry, error) {
+	return h.query(`ORDER BY ts DESC LIMIT ?`, limit)
+}
+
+// Today returns commands run since the start of the current day.
+func (h *HistoryStore) Today() ([]HistoryEntry, error) {
+	startOfDay := time.Now().Truncate(24 * time.Hour).Unix()
+	return h.query(`WHERE ts >= ? ORDER BY ts DESC`, startOfDay)
+}
+
+// Cwd returns commands run from the given working directory.
+func (h *HistoryStore) Cwd(cwd string) ([]HistoryEntry, error) {
+	return h.query(`WHERE cwd = ? ORDER BY ts DESC`, cwd)
+}
+
+// Failed returns commands that exited with a non-zero status.
+func (h *HistoryStore) Failed() ([]HistoryEntry, error) {
+	return h.query(`WHERE exit_code != 0 ORDER BY ts DESC`)
+}
+
+// Search returns commands whose text contains substr, most recent first.
+func (h *HistoryStore) Search(substr string) ([]HistoryEntry, error) {
+	return h.query(`WHERE cmd LIKE ? ORDER BY ts DESC LIMIT 50`, "%"+substr+"%")
+}
+
+// printHistory renders entries one per line, colouring failed commands red.
+func printHistory(entries []HistoryEntry) {
+	for _, e := range entries {
+		line := fmt.Sprintf("%d  %s  %s", e.ID, time.Unix(e.Ts, 0).Format("2006-01-02 15:04:05"), e.Cmd)
+		if e.ExitCode != 0 {
+			fmt.Println(ansiRed + line + ansiReset)
+		} else {
+			fmt.Println(line)
+		}
+	}
+}
+
+// seedLinerHistory backfills liner's in-memory ring with the most recent
+// commands from store, oldest first, so Ctrl-R's reverse-isearch covers more
+// than whatever is already in l (session history plus history_file).
+// liner deduplicates consecutive repeats itself, so re-appending entries
+// already loaded from history_file is harmless.
+func seedLinerHistory(l *liner.State, store *HistoryStore) {
+	entries, err := store.Recent(seedLinerHistoryLimit)
+	if err != nil {
+		return
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		l.AppendHistory(entries[i].Cmd)
+	}
+}
+
+// executeHistoryCommand implements the "history" built-in and its
+// subcommands: plain listing, --today, --cwd, --failed, and search <substr>.
+func executeHistoryCommand(store *HistoryStore, args []string) (int, error) {
+	if store == nil {
+		return 1, fmt.Errorf("history: store unavailable")
+	}
+
+	var (
+		entries []HistoryEntry
+		err     error
+	)
+
+	switch {
+	case len(args) == 0:
+		entries, err = store.Recent(50)
+	case args[0] == "--today":
+		entries, err = store.Today()
+	case args[0] == "--cwd":
+		cwd, cwdErr := os.Getwd()
+		if cwdErr != nil {
+			return 1, cwdErr
+		}
+		entries, err = store.Cwd(cwd)
+	case args[0] == "--failed":
+		entries, err = store.Failed()
+	case args[0] == "search":
+		if len(args) < 2 {
+			return 1, fmt.Errorf("history search: missing <substr>")
+		}
+		entries, err = store.Search(args[1])
+	default:
+		return 1, fmt.Errorf("history: unknown subcommand '%s'", args[0])
+	}
+
+	if err != nil {
+		return 1, err
+	}
+
+	printHistory(entries)
+	return 0, nil
+}
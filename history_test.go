@@ -0,0 +1,131 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestHistoryStore(t *testing.T) *HistoryStore {
+	t.Helper()
+	store, err := openHistoryStore(":memory:")
+	if err != nil {
+		t.Fatalf("openHistoryStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestHistoryStoreRecentOrdersNewestFirst(t *testing.T) {
+	store := newTestHistoryStore(t)
+	now := time.Now().Unix()
+
+	insert := func(cmd string, ts int64, exitCode int, cwd string) {
+		if err := store.Insert(HistoryEntry{Cmd: cmd, Ts: ts, Cwd: cwd, ExitCode: exitCode}); err != nil {
+			t.Fatalf("Insert(%q): %v", cmd, err)
+		}
+	}
+	insert("echo a", now-20, 0, "/tmp")
+	insert("echo b", now-10, 1, "/home")
+	insert("echo c", now, 0, "/tmp")
+
+	entries, err := store.Recent(10)
+	if err != nil {
+		t.Fatalf("Recent: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("len(entries) = %d, want 3", len(entries))
+	}
+	if entries[0].Cmd != "echo c" || entries[2].Cmd != "echo a" {
+		t.Errorf("Recent order = %v, want newest first", entries)
+	}
+
+	limited, err := store.Recent(1)
+	if err != nil {
+		t.Fatalf("Recent(1): %v", err)
+	}
+	if len(limited) != 1 || limited[0].Cmd != "echo c" {
+		t.Errorf("Recent(1) = %v, want just the newest row", limited)
+	}
+}
+
+func TestHistoryStoreCwd(t *testing.T) {
+	store := newTestHistoryStore(t)
+	store.Insert(HistoryEntry{Cmd: "a", Cwd: "/tmp", Ts: 1})
+	store.Insert(HistoryEntry{Cmd: "b", Cwd: "/home", Ts: 2})
+	store.Insert(HistoryEntry{Cmd: "c", Cwd: "/tmp", Ts: 3})
+
+	entries, err := store.Cwd("/tmp")
+	if err != nil {
+		t.Fatalf("Cwd: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("len(entries) = %d, want 2", len(entries))
+	}
+	for _, e := range entries {
+		if e.Cwd != "/tmp" {
+			t.Errorf("entry %+v not filtered to /tmp", e)
+		}
+	}
+}
+
+func TestHistoryStoreFailed(t *testing.T) {
+	store := newTestHistoryStore(t)
+	store.Insert(HistoryEntry{Cmd: "ok", Ts: 1, ExitCode: 0})
+	store.Insert(HistoryEntry{Cmd: "bad", Ts: 2, ExitCode: 1})
+
+	entries, err := store.Failed()
+	if err != nil {
+		t.Fatalf("Failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Cmd != "bad" {
+		t.Errorf("Failed() = %v, want just the non-zero-exit row", entries)
+	}
+}
+
+func TestHistoryStoreToday(t *testing.T) {
+	store := newTestHistoryStore(t)
+	yesterday := time.Now().Add(-48 * time.Hour).Unix()
+	store.Insert(HistoryEntry{Cmd: "old", Ts: yesterday})
+	store.Insert(HistoryEntry{Cmd: "new", Ts: time.Now().Unix()})
+
+	entries, err := store.Today()
+	if err != nil {
+		t.Fatalf("Today: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Cmd != "new" {
+		t.Errorf("Today() = %v, want just today's row", entries)
+	}
+}
+
+func TestHistoryStoreSearch(t *testing.T) {
+	store := newTestHistoryStore(t)
+	store.Insert(HistoryEntry{Cmd: "git status", Ts: 1})
+	store.Insert(HistoryEntry{Cmd: "git commit", Ts: 2})
+	store.Insert(HistoryEntry{Cmd: "ls -la", Ts: 3})
+
+	entries, err := store.Search("git")
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("len(entries) = %d, want 2", len(entries))
+	}
+	if entries[0].Cmd != "git commit" {
+		t.Errorf("Search() order = %v, want newest first", entries)
+	}
+}
+
+func TestExecuteHistoryCommandUnknownSubcommand(t *testing.T) {
+	store := newTestHistoryStore(t)
+	code, err := executeHistoryCommand(store, []string{"--bogus"})
+	if err == nil || code != 1 {
+		t.Errorf("executeHistoryCommand(--bogus) = (%d, %v), want (1, error)", code, err)
+	}
+}
+
+func TestExecuteHistoryCommandNoStore(t *testing.T) {
+	code, err := executeHistoryCommand(nil, nil)
+	if err == nil || code != 1 {
+		t.Errorf("executeHistoryCommand(nil store) = (%d, %v), want (1, error)", code, err)
+	}
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// JobState is the run state of a backgrounded pipeline.
+type JobState int
+
+const (
+	JobRunning JobState = iota
+	JobStopped
+	JobDone
+)
+
+func (s JobState) String() string {
+	switch s {
+	case JobRunning:
+		return "Running"
+	case JobStopped:
+		return "Stopped"
+	case JobDone:
+		return "Done"
+	default:
+		return "Unknown"
+	}
+}
+
+// Job is one backgrounded pipeline tracked by %N job IDs. Job pointers are
+// handed out by JobTable.Get/List and read or mutated well after the table's
+// own lock has been released (executeFgCommand, the background-wait
+// goroutine in executor.go, ...), so state/exitCode need their own lock
+// rather than riding on JobTable.mu.
+type Job struct {
+	ID      int
+	PGID    int
+	Cmds    []*exec.Cmd
+	CmdLine string
+	done    chan struct{}
+
+	mu       sync.Mutex
+	state    JobState
+	exitCode int
+}
+
+// State returns the job's current run state.
+func (j *Job) State() JobState {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.state
+}
+
+// setState updates the job's run state, e.g. when "fg"/"bg" resumes a
+// stopped job.
+func (j *Job) setState(state JobState) {
+	j.mu.Lock()
+	j.state = state
+	j.mu.Unlock()
+}
+
+// ExitCode returns the exit code recorded for a Done job.
+func (j *Job) ExitCode() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.exitCode
+}
+
+// finish records a job's exit code and marks it Done.
+func (j *Job) finish(exitCode int) {
+	j.mu.Lock()
+	j.exitCode = exitCode
+	j.state = JobDone
+	j.mu.Unlock()
+}
+
+// JobTable tracks background jobs by sequential %1, %2, ... IDs.
+type JobTable struct {
+	mu     sync.Mutex
+	jobs   map[int]*Job
+	nextID int
+}
+
+func newJobTable() *JobTable {
+	return &JobTable{jobs: make(map[int]*Job), nextID: 1}
+}
+
+var jobTable = newJobTable()
+
+// Add registers a newly started background pipeline and returns its Job.
+func (t *JobTable) Add(cmds []*exec.Cmd, pgid int, cmdLine string) *Job {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	job := &Job{
+		ID:      t.nextID,
+		PGID:    pgid,
+		Cmds:    cmds,
+		state:   JobRunning,
+		CmdLine: cmdLine,
+		done:    make(chan struct{}),
+	}
+	t.jobs[job.ID] = job
+	t.nextID++
+	return job
+}
+
+// Finish marks a job Done, records its exit code, and queues a "Done"
+// notification for the next prompt.
+func (t *JobTable) Finish(id int, exitCode int) {
+	t.mu.Lock()
+	job, ok := t.jobs[id]
+	t.mu.Unlock()
+	if !ok {
+		return
+	}
+	job.finish(exitCode)
+	close(job.done)
+	jobDone <- job
+}
+
+// Get returns the job registered under id.
+func (t *JobTable) Get(id int) (*Job, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	job, ok := t.jobs[id]
+	return job, ok
+}
+
+// List returns every tracked job, ordered by ID.
+func (t *JobTable) List() []*Job {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	jobs := make([]*Job, 0, len(t.jobs))
+	for _, job := range t.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+	return jobs
+}
+
+// Remove drops a job from the table, e.g. once "fg" has reaped it.
+func (t *JobTable) Remove(id int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.jobs, id)
+}
+
+// jobDone carries jobs whose goroutine has finished Wait()-ing; the main
+// loop drains it before drawing the next prompt so "[1]+ Done ..." shows
+// up promptly instead of mid-typing.
+var jobDone = make(chan *Job, 16)
+
+// drainJobNotifications prints a "Done" line for every background job that
+// finished since the last prompt, then reaps it: once the one-shot
+// notification has been shown, a Done job has nothing left to report and
+// shouldn't keep cluttering "jobs" output forever.
+func drainJobNotifications() {
+	for {
+		select {
+		case job := <-jobDone:
+			fmt.Printf("[%d]+  Done    %s\n", job.ID, job.CmdLine)
+			jobTable.Remove(job.ID)
+		default:
+			return
+		}
+	}
+}
+
+var shellPGID int
+
+var foregroundMu sync.Mutex
+var foregroundPGID int
+
+// setForegroundPGID records which process group Ctrl-C should be forwarded
+// to. 0 means "no foreground job" (Ctrl-C is a no-op at the prompt; liner
+// handles line-editing aborts itself).
+func setForegroundPGID(pgid int) {
+	foregroundMu.Lock()
+	foregroundPGID = pgid
+	foregroundMu.Unlock()
+}
+
+func getForegroundPGID() int {
+	foregroundMu.Lock()
+	defer foregroundMu.Unlock()
+	return foregroundPGID
+}
+
+// installSignalHandlers records the shell's own process group and starts
+// forwarding Ctrl-C to whichever pipeline is currently in the foreground,
+// instead of letting it hit the shell process itself.
+func installSignalHandlers() {
+	shellPGID, _ = syscall.Getpgid(0)
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGCHLD)
+	go func() {
+		for sig := range sigChan {
+			if sig == os.Interrupt {
+				if pgid := getForegroundPGID(); pgid != 0 {
+					syscall.Kill(-pgid, syscall.SIGINT)
+				}
+			}
+			// SIGCHLD itself needs no action here: each job's own
+			// goroutine reaps it via Cmd.Wait(). Receiving it just
+			// guarantees drainJobNotifications has fresh state to
+			// print by the time the next prompt is drawn.
+		}
+	}()
+}
+
+// tcSetForeground hands the controlling terminal to pgid, the same way a
+// real shell does when putting a job in the foreground.
+func tcSetForeground(pgid int) error {
+	return unix.IoctlSetPointerInt(int(os.Stdin.Fd()), unix.TIOCSPGRP, pgid)
+}
+
+// parseJobArg parses a "%N" job reference.
+func parseJobArg(arg string) (int, bool) {
+	if !strings.HasPrefix(arg, "%") {
+		return 0, false
+	}
+	id, err := strconv.Atoi(arg[1:])
+	if err != nil {
+		return 0, false
+	}
+	return id, true
+}
+
+// resolveJob finds the job named by args[0] ("%N"), or the most recently
+// started Running/Stopped job when no argument is given. A job lingers in
+// the table, Done, until its notification has been printed, so the highest
+// ID isn't necessarily still current; skip over Done jobs when searching.
+func resolveJob(args []string) (*Job, error) {
+	if len(args) == 0 {
+		jobs := jobTable.List()
+		for i := len(jobs) - 1; i >= 0; i-- {
+			if jobs[i].State() != JobDone {
+				return jobs[i], nil
+			}
+		}
+		return nil, fmt.Errorf("no current job")
+	}
+	id, ok := parseJobArg(args[0])
+	if !ok {
+		return nil, fmt.Errorf("usage: %%N")
+	}
+	job, ok := jobTable.Get(id)
+	if !ok {
+		return nil, fmt.Errorf("no such job %s", args[0])
+	}
+	return job, nil
+}
+
+// executeJobsCommand implements the "jobs" built-in: list every tracked
+// background job and its state.
+func executeJobsCommand() (int, error) {
+	for _, job := range jobTable.List() {
+		fmt.Printf("[%d]  %-8s %s\n", job.ID, job.State(), job.CmdLine)
+	}
+	return 0, nil
+}
+
+// executeFgCommand implements "fg [%n]": resume the job if stopped, give
+// it the terminal, and block until it finishes.
+func executeFgCommand(args []string) (int, error) {
+	job, err := resolveJob(args)
+	if err != nil {
+		return 1, err
+	}
+
+	if err := tcSetForeground(job.PGID); err != nil {
+		return 1, err
+	}
+	setForegroundPGID(job.PGID)
+	defer func() {
+		setForegroundPGID(0)
+		tcSetForeground(shellPGID)
+	}()
+
+	syscall.Kill(-job.PGID, syscall.SIGCONT)
+	job.setState(JobRunning)
+	fmt.Println(job.CmdLine)
+
+	<-job.done
+	jobTable.Remove(job.ID)
+	return job.ExitCode(), nil
+}
+
+// executeBgCommand implements "bg [%n]": resume a stopped job without
+// taking the terminal away from the shell.
+func executeBgCommand(args []string) (int, error) {
+	job, err := resolveJob(args)
+	if err != nil {
+		return 1, err
+	}
+	if err := syscall.Kill(-job.PGID, syscall.SIGCONT); err != nil {
+		return 1, err
+	}
+	job.setState(JobRunning)
+	fmt.Printf("[%d]+ %s &\n", job.ID, job.CmdLine)
+	return 0, nil
+}
+
+// executeKillCommand implements "kill [-signal] %n|pid".
+func executeKillCommand(args []string) (int, error) {
+	sig := syscall.SIGTERM
+	if len(args) > 1 && strings.HasPrefix(args[0], "-") {
+		if s, ok := parseSignal(args[0][1:]); ok {
+			sig = s
+		}
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return 1, fmt.Errorf("kill: usage: kill [-signal] %%n|pid")
+	}
+
+	if id, ok := parseJobArg(args[0]); ok {
+		job, ok := jobTable.Get(id)
+		if !ok {
+			return 1, fmt.Errorf("kill: no such job %s", args[0])
+		}
+		if err := syscall.Kill(-job.PGID, sig); err != nil {
+			return 1, err
+		}
+		return 0, nil
+	}
+
+	pid, err := strconv.Atoi(args[0])
+	if err != nil {
+		return 1, fmt.Errorf("kill: invalid pid or job: %s", args[0])
+	}
+	if err := syscall.Kill(pid, sig); err != nil {
+		return 1, err
+	}
+	return 0, nil
+}
+
+// parseSignal accepts a bare signal number or a name like "KILL"/"SIGKILL".
+func parseSignal(s string) (syscall.Signal, bool) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return syscall.Signal(n), true
+	}
+	switch strings.ToUpper(s) {
+	case "SIGTERM", "TERM":
+		return syscall.SIGTERM, true
+	case "SIGKILL", "KILL":
+		return syscall.SIGKILL, true
+	case "SIGINT", "INT":
+		return syscall.SIGINT, true
+	case "SIGSTOP", "STOP":
+		return syscall.SIGSTOP, true
+	case "SIGCONT", "CONT":
+		return syscall.SIGCONT, true
+	case "SIGHUP", "HUP":
+		return syscall.SIGHUP, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+func TestParseJobArg(t *testing.T) {
+	cases := []struct {
+		arg    string
+		wantID int
+		wantOK bool
+	}{
+		{"%1", 1, true},
+		{"%42", 42, true},
+		{"1", 0, false},
+		{"%x", 0, false},
+	}
+	for _, c := range cases {
+		id, ok := parseJobArg(c.arg)
+		if id != c.wantID || ok != c.wantOK {
+			t.Errorf("parseJobArg(%q) = (%d, %v), want (%d, %v)", c.arg, id, ok, c.wantID, c.wantOK)
+		}
+	}
+}
+
+func TestParseSignal(t *testing.T) {
+	cases := []struct {
+		in   string
+		want syscall.Signal
+		ok   bool
+	}{
+		{"9", 9, true},
+		{"KILL", syscall.SIGKILL, true},
+		{"SIGTERM", syscall.SIGTERM, true},
+		{"cont", syscall.SIGCONT, true},
+		{"bogus", 0, false},
+	}
+	for _, c := range cases {
+		got, ok := parseSignal(c.in)
+		if got != c.want || ok != c.ok {
+			t.Errorf("parseSignal(%q) = (%v, %v), want (%v, %v)", c.in, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestJobTableAddFinishList(t *testing.T) {
+	table := newJobTable()
+
+	j1 := table.Add(nil, 111, "sleep 1")
+	j2 := table.Add(nil, 222, "sleep 2")
+
+	jobs := table.List()
+	if len(jobs) != 2 || jobs[0].ID != j1.ID || jobs[1].ID != j2.ID {
+		t.Fatalf("List() = %+v, want [%d %d] in order", jobs, j1.ID, j2.ID)
+	}
+
+	go table.Finish(j1.ID, 0)
+	done := <-jobDone
+	if done.ID != j1.ID || done.State() != JobDone {
+		t.Errorf("Finish notification = job %d state %v, want job %d Done", done.ID, done.State(), j1.ID)
+	}
+
+	got, ok := table.Get(j1.ID)
+	if !ok || got.State() != JobDone || got.ExitCode() != 0 {
+		t.Errorf("Get(%d) = (state %v, exit %d), want (Done, 0)", j1.ID, got.State(), got.ExitCode())
+	}
+
+	table.Remove(j1.ID)
+	if _, ok := table.Get(j1.ID); ok {
+		t.Errorf("Get(%d) found a job after Remove", j1.ID)
+	}
+	if jobs := table.List(); len(jobs) != 1 || jobs[0].ID != j2.ID {
+		t.Errorf("List() after Remove = %+v, want just job %d", jobs, j2.ID)
+	}
+}
+
+func TestResolveJobSkipsDoneJobs(t *testing.T) {
+	origTable := jobTable
+	defer func() { jobTable = origTable }()
+	jobTable = newJobTable()
+
+	running := jobTable.Add(nil, 111, "sleep 100")
+	done := jobTable.Add(nil, 222, "sleep 1")
+	jobTable.jobs[done.ID].setState(JobDone)
+
+	job, err := resolveJob(nil)
+	if err != nil {
+		t.Fatalf("resolveJob(nil): %v", err)
+	}
+	if job.ID != running.ID {
+		t.Errorf("resolveJob(nil) = job %d, want the still-running job %d", job.ID, running.ID)
+	}
+}
+
+func TestResolveJobNoCurrentJob(t *testing.T) {
+	origTable := jobTable
+	defer func() { jobTable = origTable }()
+	jobTable = newJobTable()
+
+	if _, err := resolveJob(nil); err == nil {
+		t.Fatal("resolveJob(nil) on an empty table: expected an error")
+	}
+}
+
+func TestResolveJobByID(t *testing.T) {
+	origTable := jobTable
+	defer func() { jobTable = origTable }()
+	jobTable = newJobTable()
+
+	job := jobTable.Add(nil, 111, "sleep 100")
+
+	got, err := resolveJob([]string{"%" + strconv.Itoa(job.ID)})
+	if err != nil {
+		t.Fatalf("resolveJob(%%N): %v", err)
+	}
+	if got.ID != job.ID {
+		t.Errorf("resolveJob(%%N) = job %d, want %d", got.ID, job.ID)
+	}
+
+	if _, err := resolveJob([]string{"%999"}); err == nil {
+		t.Fatal("resolveJob(%999) on a missing job: expected an error")
+	}
+}
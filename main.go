@@ -1,264 +1,251 @@
 package main
 
 import (
-	"bufio"
-	"context"
-	"errors"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
-	"os/signal"
 	"strings"
+	"time"
+
+	"github.com/peterh/liner"
+
+	"github.com/daniela-veloz/goshell/shell/lua"
 )
 
 var history_file = os.Getenv("HOME") + "/.gocsh_history"
-var historyFile *os.File
-
-type Command struct {
-	name string
-	args []string
+var lua_config_file = os.Getenv("HOME") + "/.gocshrc.lua"
+var line *liner.State
+var historyStore *HistoryStore
+var luaRuntime *lua.Runtime
+
+// initLiner sets up the liner.State used for the interactive prompt: Ctrl-C
+// aborts the current line instead of killing the shell, history is loaded
+// from history_file, and tab completion is wired up.
+func initLiner() *liner.State {
+	l := liner.NewLiner()
+	l.SetCtrlCAborts(true)
+	l.SetCompleter(completer)
+	// Ctrl-R is wired into liner itself and only ever searches liner's own
+	// in-memory ring; liner exposes no hook to redirect it at a custom
+	// source. Rather than leave Ctrl-R shallow, seedLinerHistory below
+	// backfills that ring from historyStore so recall covers more than
+	// just the current session. Full filtered/substring search over the
+	// entire SQLite history goes through `history search <substr>`.
+
+	if f, err := os.Open(history_file); err == nil {
+		l.ReadHistory(f)
+		f.Close()
+	}
+
+	return l
 }
 
-func parseInput(input string) ([]Command, error) {
-	// remove empty spaces from the input
-	input = strings.TrimSpace(input)
-
-	// return empty command slice if input is empty
-	if input == "" {
-		return []Command{}, nil
+// closeLiner persists history to history_file and releases the terminal.
+func closeLiner(l *liner.State) {
+	if f, err := os.Create(history_file); err == nil {
+		l.WriteHistory(f)
+		f.Close()
 	}
+	l.Close()
+}
 
-	// split input by |
-	pipedInputs := strings.Split(input, "|")
-	commands := make([]Command, 0, len(pipedInputs))
-
-	// per each piped command identify command and args
-	for _, pipedInput := range pipedInputs {
-		pipedInput := strings.TrimSpace(pipedInput)
-		parts := strings.Fields(pipedInput)
+// completer implements liner's tab completion: built-in names, executables
+// on $PATH, and filesystem entries for anything after the first token.
+func completer(input string) []string {
+	lastSpace := strings.LastIndex(input, " ")
+	prefix := input[lastSpace+1:]
+	head := input[:lastSpace+1]
+
+	var candidates []string
+	if lastSpace == -1 {
+		candidates = append(candidates, completeBuiltins(prefix)...)
+		candidates = append(candidates, completeExecutables(prefix)...)
+	} else {
+		candidates = append(candidates, completePaths(prefix)...)
+	}
 
-		if len(parts) == 0 {
-			return []Command{}, fmt.Errorf("invalid input: %s", pipedInput)
-		}
-		command := parts[0]
-		args := parts[1:]
-		commands = append(commands, Command{command, args})
+	completions := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		completions = append(completions, head+c)
 	}
-	return commands, nil
+	return completions
 }
 
-func initHistory() error {
-	var err error
-	historyFile, err = os.OpenFile(history_file, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
-	return err
+func completeBuiltins(prefix string) []string {
+	builtins := []string{"cd", "exit", "history", "jobs", "fg", "bg", "kill"}
+	var matches []string
+	for _, b := range builtins {
+		if strings.HasPrefix(b, prefix) {
+			matches = append(matches, b)
+		}
+	}
+	return matches
 }
 
-func saveHistory(input string) error {
-	if historyFile == nil {
-		return nil // History disabled
+func completeExecutables(prefix string) []string {
+	var matches []string
+	for _, dir := range strings.Split(os.Getenv("PATH"), string(os.PathListSeparator)) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if strings.HasPrefix(entry.Name(), prefix) {
+				matches = append(matches, entry.Name())
+			}
+		}
 	}
-	_, err := historyFile.WriteString(input)
-	return err
+	return matches
 }
 
-func closeHistory() {
-	if historyFile != nil {
-		historyFile.Close()
+func completePaths(prefix string) []string {
+	dir, filePrefix := "./", prefix
+	if idx := strings.LastIndex(prefix, "/"); idx != -1 {
+		dir = prefix[:idx+1]
+		filePrefix = prefix[idx+1:]
 	}
-}
 
-func displayHistory() error {
-	file, err := os.Open(history_file)
+	entries, err := os.ReadDir(dir)
 	if err != nil {
-		return err
+		return nil
 	}
-	defer file.Close()
-
-	_, err = io.Copy(os.Stdout, file)
-	return err
-}
 
-func shouldBeInHistory(commands []Command) bool {
-	if len(commands) == 0 {
-		return false // Empty commands should not be saved
-	}
-	if len(commands) > 1 {
-		return true // Save piped commands to history
+	var matches []string
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), filePrefix) {
+			continue
+		}
+		name := dir + entry.Name()
+		if entry.IsDir() {
+			name += "/"
+		}
+		matches = append(matches, name)
 	}
-	// Don't save "history" or "exit" commands
-	return commands[0].name != "history" && commands[0].name != "exit"
-
+	return matches
 }
 
-// setupSignalHandler creates a context that will be cancelled when CTRL+C is pressed.
-// Returns the context and a cleanup function that should be deferred.
-func setupSignalHandler() (context.Context, context.CancelFunc) {
-	ctx, cancel := context.WithCancel(context.Background())
-
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt)
-
-	go func() {
-		<-sigChan
-		cancel()
-	}()
-
-	// Return a wrapped cancel function that also stops signal notifications
-	cleanup := func() {
-		signal.Stop(sigChan)
-		cancel()
+// shouldBeInHistory reports whether node is worth recording: everything
+// except a bare "history" or "exit" invocation.
+func shouldBeInHistory(node Node) bool {
+	if node == nil {
+		return false // Empty input should not be saved
 	}
-
-	return ctx, cleanup
-}
-
-// handleCommandError checks if an error is due to context cancellation (CTRL+C).
-// If so, it prints a newline and returns nil. Otherwise, it returns the original error.
-func handleCommandError(ctx context.Context, err error) error {
-	if err != nil && errors.Is(ctx.Err(), context.Canceled) {
-		fmt.Println() // Print newline after ^C
-		return nil    // Don't treat ^C as an error
+	if bg, ok := node.(*Background); ok {
+		node = bg.inner
+	}
+	if p, ok := node.(*Pipeline); ok && len(p.cmds) == 1 {
+		return p.cmds[0].name != "history" && p.cmds[0].name != "exit"
 	}
-	return err
+	return true
 }
 
-// executeNotBuiltInCommand executes commands using the computer's OS.
-func executeNotBuiltInCommand(command string, args []string) error {
-	ctx, cleanup := setupSignalHandler()
-	defer cleanup()
-
-	cmd := exec.CommandContext(ctx, command, args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
-
-	err := cmd.Run()
-	return handleCommandError(ctx, err)
-}
+func main() {
+	line = initLiner()
+	defer closeLiner(line)
 
-// executeCdCommand executes the "cd" built-in command to change directories.
-func executeCdCommand(args []string) error {
-	var path string
-	if len(args) == 0 { // if no path is defined it defaults to $HOME
-		path = os.Getenv("HOME")
+	var err error
+	historyStore, err = openHistoryStore(history_db_file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not open history database: %v\n", err)
 	} else {
-		path = args[0]
+		defer historyStore.Close()
+		seedLinerHistory(line, historyStore)
 	}
-	return os.Chdir(path)
-}
 
-// executeSingleCommand executes a single command. Built-in commands cannot be part of pipes.
-func executeSingleCommand(command Command) error {
-	switch command.name {
-	case "":
-		return nil
-	case "exit":
-		os.Exit(0)
-		return nil
-	case "cd":
-		return executeCdCommand(command.args)
-	case "history":
-		return displayHistory()
-	default:
-		return executeNotBuiltInCommand(command.name, command.args)
-	}
-}
-
-// executePipeline executes a series of piped commands.
-func executePipeline(commands []Command) error {
-	if len(commands) == 0 {
-		return nil
-	}
-	if len(commands) == 1 {
-		return executeSingleCommand(commands[0])
+	luaRuntime = lua.NewRuntime()
+	defer luaRuntime.Close()
+	if err := luaRuntime.LoadConfig(lua_config_file); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not load %s: %v\n", lua_config_file, err)
 	}
 
-	// Create context so it can be cancelled
-	ctx, cleanup := setupSignalHandler()
-	defer cleanup()
+	installSignalHandlers()
+	executor := NewExecutor()
 
-	// Check for built-in commands in pipeline
-	for _, cmd := range commands {
-		if cmd.name == "cd" || cmd.name == "exit" {
-			return fmt.Errorf("cannot use built-in command '%s' in pipeline", cmd.name)
+	for {
+		// flush "[1]+ Done ..." notifications for background jobs that
+		// finished since the last prompt
+		drainJobNotifications()
+
+		// read the keyboard string, rendering the prompt (overridable from
+		// Lua via prompt(...)) with full line editing, history recall, and
+		// tab completion
+		input, err := line.Prompt(luaRuntime.Prompt("> "))
+		if err == io.EOF {
+			fmt.Println()
+			break
 		}
-	}
-
-	// create commands
-	var cmds []*exec.Cmd //slice of pointers to exec.Cmd so we can modify them later
-	for _, command := range commands {
-		cmd := exec.CommandContext(ctx, command.name, command.args...)
-		cmds = append(cmds, cmd)
-	}
-
-	// Connect the output of each command to the input of the next command
-	// the last command has no "next" command to connect to
-	for i := 0; i < len(cmds)-1; i++ {
-		stdout, err := cmds[i].StdoutPipe()
-		if err != nil {
-			return err
+		if err == liner.ErrPromptAborted {
+			fmt.Println()
+			continue
 		}
-		cmds[i+1].Stdin = stdout
-	}
-
-	// Set first command stdin and last command stdout to the terminal
-	cmds[0].Stdin = os.Stdin
-	cmds[len(cmds)-1].Stdout = os.Stdout
-	cmds[len(cmds)-1].Stderr = os.Stderr
-
-	// Start all commands
-	// we use use Start(non-blocking) instead of Run(blocking), we need all cmds running in parallel so next command
-	// can read from the prev pipe
-	for _, cmd := range cmds {
-		if err := cmd.Start(); err != nil {
-			return err
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			continue
 		}
-	}
 
-	// Wait for all commands
-	for _, cmd := range cmds {
-		if err := cmd.Wait(); err != nil {
-			return handleCommandError(ctx, err)
+		// try the line as Lua first, so users can mix Lua snippets and
+		// shell commands at the prompt. Only syntax errors (not valid Lua
+		// at all) fall back to shell parsing; Lua runtime errors are
+		// reported as-is.
+		if strings.TrimSpace(input) != "" {
+			if err := luaRuntime.Eval(input); err == nil || !lua.IsSyntaxError(err) {
+				if err != nil {
+					fmt.Println(err)
+				}
+				line.AppendHistory(input)
+				continue
+			}
 		}
-	}
-
-	return nil
-}
-
-func main() {
-	// Initialize history file
-	if err := initHistory(); err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: could not open history: %v\n", err)
-	}
-	defer closeHistory()
-
-	// read a line of input from the user
-	reader := bufio.NewReader(os.Stdin)
 
-	for {
-		// display the prompt
-		fmt.Print("> ")
-		// read the keyboard string
-		input, _ := reader.ReadString('\n')
-
-		// parse input to Command
-		commands, err := parseInput(input)
+		// parse input into the command AST
+		node, err := ParseInput(input)
 		if err != nil {
 			fmt.Println(err)
 			continue
 		}
+		if node == nil {
+			continue // blank input
+		}
 
-		if err := executePipeline(commands); err != nil {
+		luaRuntime.FireHook("command.pre", input)
+
+		start := time.Now()
+		exitCode, err := executor.Run(node)
+		duration := time.Since(start)
+		if err != nil {
 			fmt.Println(err)
+			luaRuntime.FireHook("command.fail", input)
+		} else {
+			luaRuntime.FireHook("command.success", input)
 		}
 
 		// save to history
-		if shouldBeInHistory(commands) {
-			if err := saveHistory(input); err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: could not write to history: %v\n", err)
-			}
+		if shouldBeInHistory(node) {
+			line.AppendHistory(input)
+			recordHistory(input, exitCode, duration)
 		}
 	}
 
 }
+
+// recordHistory inserts a completed command into the SQLite history store,
+// capturing the metadata the flat history file never could.
+func recordHistory(input string, exitCode int, duration time.Duration) {
+	if historyStore == nil {
+		return
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		cwd = ""
+	}
+	entry := HistoryEntry{
+		Cmd:        strings.TrimSpace(input),
+		Ts:         time.Now().Unix(),
+		Cwd:        cwd,
+		ExitCode:   exitCode,
+		DurationMs: duration.Milliseconds(),
+	}
+	if err := historyStore.Insert(entry); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not write to history: %v\n", err)
+	}
+}
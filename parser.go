@@ -0,0 +1,474 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Command is a single program invocation: its name, arguments, and any
+// redirections attached to it. Built-in commands (cd, exit, history) can
+// only appear as the sole command of a Pipeline.
+type Command struct {
+	name   string
+	args   []string
+	redirs []Redir
+}
+
+// Redir is one redirection attached to a Command, e.g. `> out.txt`,
+// `2>&1`, or `< in.txt`.
+type Redir struct {
+	fd     int    // source file descriptor: 0 for <, 1 for >/>>, 2 for 2>, ...
+	append bool   // true for >>
+	dupFd  int    // destination fd for "N>&M" duplication, or -1 when unused
+	target string // filename; unused when dupFd >= 0
+}
+
+// Node is one element of the parsed command AST.
+type Node interface {
+	node()
+}
+
+// Pipeline is one or more commands connected by "|".
+type Pipeline struct {
+	cmds []Command
+}
+
+// Sequence runs every node in order regardless of exit status, like ";".
+type Sequence struct {
+	nodes []Node
+}
+
+// AndList runs right only if left exits successfully, like "&&".
+type AndList struct {
+	left, right Node
+}
+
+// OrList runs right only if left exits with failure, like "||".
+type OrList struct {
+	left, right Node
+}
+
+// Background runs node asynchronously: the shell backgrounds it as a job
+// and returns to the prompt immediately instead of waiting, like a
+// trailing "&".
+type Background struct {
+	inner Node
+}
+
+func (*Pipeline) node()   {}
+func (*Sequence) node()   {}
+func (*AndList) node()    {}
+func (*OrList) node()     {}
+func (*Background) node() {}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokPipe
+	tokSemi
+	tokAnd
+	tokOr
+	tokRedirIn
+	tokRedirOut
+	tokRedirAppend
+	tokDup
+	tokAmp
+)
+
+type token struct {
+	kind   tokenKind
+	value  string // word text, expansions already applied
+	quoted bool   // word came from a quoted fragment: no glob expansion
+	fd     int    // source fd for redirection tokens
+	dupFd  int    // destination fd for tokDup
+}
+
+// ParseInput tokenizes and parses a line of input into a Node the Executor
+// can run. It returns (nil, nil) for blank input.
+func ParseInput(input string) (Node, error) {
+	if strings.TrimSpace(input) == "" {
+		return nil, nil
+	}
+
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	node, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokEOF {
+		return nil, fmt.Errorf("unexpected token near %q", p.peek().value)
+	}
+	return node, nil
+}
+
+// tokenize turns raw input into a flat token stream: quotes and escapes are
+// resolved, $VAR/${VAR} and ~ are expanded, and shell operators
+// (| ; && || < > >> 2> 2>&1) become distinct token kinds.
+func tokenize(input string) ([]token, error) {
+	r := []rune(input)
+	n := len(r)
+	i := 0
+	var tokens []token
+
+	readFd := func() int {
+		start := i
+		for i < n && r[i] >= '0' && r[i] <= '9' {
+			i++
+		}
+		fd, _ := strconv.Atoi(string(r[start:i]))
+		return fd
+	}
+
+	readRedirOp := func(fd int) token {
+		switch r[i] {
+		case '<':
+			i++
+			return token{kind: tokRedirIn, fd: fd}
+		case '>':
+			i++
+			if i < n && r[i] == '>' {
+				i++
+				return token{kind: tokRedirAppend, fd: fd}
+			}
+			if i < n && r[i] == '&' && i+1 < n && r[i+1] >= '0' && r[i+1] <= '9' {
+				i++
+				dup := readFd()
+				return token{kind: tokDup, fd: fd, dupFd: dup}
+			}
+			return token{kind: tokRedirOut, fd: fd}
+		}
+		panic("unreachable")
+	}
+
+	for i < n {
+		c := r[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == ';':
+			tokens = append(tokens, token{kind: tokSemi})
+			i++
+		case c == '|':
+			if i+1 < n && r[i+1] == '|' {
+				tokens = append(tokens, token{kind: tokOr})
+				i += 2
+			} else {
+				tokens = append(tokens, token{kind: tokPipe})
+				i++
+			}
+		case c == '&' && i+1 < n && r[i+1] == '&':
+			tokens = append(tokens, token{kind: tokAnd})
+			i += 2
+		case c == '&':
+			tokens = append(tokens, token{kind: tokAmp})
+			i++
+		case c == '<':
+			tokens = append(tokens, readRedirOp(0))
+		case c == '>':
+			tokens = append(tokens, readRedirOp(1))
+		case c >= '0' && c <= '9' && peekIsFdPrefixedRedir(r, i):
+			fd := readFd()
+			tokens = append(tokens, readRedirOp(fd))
+		default:
+			value, quoted, err := readWord(r, &i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokWord, value: value, quoted: quoted})
+		}
+	}
+
+	return tokens, nil
+}
+
+// peekIsFdPrefixedRedir reports whether the digit run starting at i is
+// immediately followed by '<' or '>', e.g. the "2" in "2>&1".
+func peekIsFdPrefixedRedir(r []rune, i int) bool {
+	n := len(r)
+	j := i
+	for j < n && r[j] >= '0' && r[j] <= '9' {
+		j++
+	}
+	return j < n && (r[j] == '<' || r[j] == '>')
+}
+
+// readWord consumes one whitespace/operator-delimited word starting at *i,
+// resolving quotes, backslash escapes, and $VAR/${VAR} expansion as it
+// goes. It reports whether any part of the word was quoted, which
+// suppresses glob and tilde expansion for the word as a whole.
+func readWord(r []rune, i *int) (string, bool, error) {
+	n := len(r)
+	var result strings.Builder
+	var unquoted strings.Builder
+	quoted := false
+
+	flush := func() {
+		if unquoted.Len() > 0 {
+			result.WriteString(expandVars(unquoted.String()))
+			unquoted.Reset()
+		}
+	}
+
+	for *i < n {
+		c := r[*i]
+		switch {
+		case c == ' ' || c == '\t' || c == ';' || c == '|' || c == '&' || c == '<' || c == '>':
+			flush()
+			return result.String(), quoted, nil
+		case c == '\'':
+			flush()
+			quoted = true
+			*i++
+			start := *i
+			for *i < n && r[*i] != '\'' {
+				*i++
+			}
+			if *i >= n {
+				return "", false, fmt.Errorf("unterminated single quote")
+			}
+			result.WriteString(string(r[start:*i]))
+			*i++
+		case c == '"':
+			flush()
+			quoted = true
+			*i++
+			var seg strings.Builder
+			for *i < n && r[*i] != '"' {
+				if r[*i] == '\\' && *i+1 < n && strings.ContainsRune(`"\$`, r[*i+1]) {
+					seg.WriteRune(r[*i+1])
+					*i += 2
+					continue
+				}
+				seg.WriteRune(r[*i])
+				*i++
+			}
+			if *i >= n {
+				return "", false, fmt.Errorf("unterminated double quote")
+			}
+			*i++
+			result.WriteString(expandVars(seg.String()))
+		case c == '\\':
+			flush()
+			*i++
+			if *i < n {
+				result.WriteRune(r[*i])
+				*i++
+			}
+		default:
+			unquoted.WriteRune(c)
+			*i++
+		}
+	}
+	flush()
+	return result.String(), quoted, nil
+}
+
+// expandVars expands $VAR and ${VAR} references using the process
+// environment.
+func expandVars(s string) string {
+	return os.Expand(s, os.Getenv)
+}
+
+// expandTilde expands a leading ~ or ~user into the relevant home
+// directory. It is a no-op for words that came from a quoted fragment.
+func expandTilde(word string, quoted bool) string {
+	if quoted || !strings.HasPrefix(word, "~") {
+		return word
+	}
+
+	rest := word[1:]
+	name, suffix, hasSlash := strings.Cut(rest, "/")
+
+	var home string
+	if name == "" {
+		home = os.Getenv("HOME")
+		if home == "" {
+			return word
+		}
+	} else {
+		var err error
+		home, err = userHomeDir(name)
+		if err != nil {
+			return word
+		}
+	}
+
+	if hasSlash {
+		return home + "/" + suffix
+	}
+	return home
+}
+
+// userHomeDir looks up the home directory of the named user for ~user
+// expansion.
+func userHomeDir(name string) (string, error) {
+	u, err := user.Lookup(name)
+	if err != nil {
+		return "", err
+	}
+	return u.HomeDir, nil
+}
+
+// expandGlob applies tilde and glob expansion to a single word, returning
+// the literal word unchanged when it is quoted or the pattern matches
+// nothing on disk.
+func expandGlob(word string, quoted bool) []string {
+	word = expandTilde(word, quoted)
+	if quoted || !strings.ContainsAny(word, "*?[") {
+		return []string{word}
+	}
+
+	matches, err := filepath.Glob(word)
+	if err != nil || len(matches) == 0 {
+		return []string{word}
+	}
+	return matches
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return token{kind: tokEOF}
+}
+
+func (p *parser) next() token {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+// parseList parses a ";"/"&"-separated list of and/or chains. A trailing
+// "&" instead of ";" backgrounds the chain that precedes it.
+func (p *parser) parseList() (Node, error) {
+	var nodes []Node
+	for {
+		n, err := p.parseAndOr()
+		if err != nil {
+			return nil, err
+		}
+
+		switch p.peek().kind {
+		case tokAmp:
+			p.next()
+			n = &Background{inner: n}
+		case tokSemi:
+			p.next()
+		default:
+			nodes = append(nodes, n)
+			if len(nodes) == 1 {
+				return nodes[0], nil
+			}
+			return &Sequence{nodes: nodes}, nil
+		}
+
+		nodes = append(nodes, n)
+		if p.peek().kind == tokEOF {
+			if len(nodes) == 1 {
+				return nodes[0], nil
+			}
+			return &Sequence{nodes: nodes}, nil
+		}
+	}
+}
+
+// parseAndOr parses a left-associative chain of pipelines joined by "&&"
+// and "||".
+func (p *parser) parseAndOr() (Node, error) {
+	left, err := p.parsePipeline()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokAnd || p.peek().kind == tokOr {
+		op := p.next().kind
+		right, err := p.parsePipeline()
+		if err != nil {
+			return nil, err
+		}
+		if op == tokAnd {
+			left = &AndList{left: left, right: right}
+		} else {
+			left = &OrList{left: left, right: right}
+		}
+	}
+	return left, nil
+}
+
+// parsePipeline parses one or more commands joined by "|".
+func (p *parser) parsePipeline() (Node, error) {
+	cmd, err := p.parseCommand()
+	if err != nil {
+		return nil, err
+	}
+	cmds := []Command{cmd}
+	for p.peek().kind == tokPipe {
+		p.next()
+		cmd, err := p.parseCommand()
+		if err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, cmd)
+	}
+	return &Pipeline{cmds: cmds}, nil
+}
+
+// parseCommand parses a single command: its words (expanded into args,
+// with glob expansion and alias substitution on the command name) and any
+// redirections attached to it.
+func (p *parser) parseCommand() (Command, error) {
+	var words []string
+	var redirs []Redir
+
+loop:
+	for {
+		t := p.peek()
+		switch t.kind {
+		case tokWord:
+			p.next()
+			words = append(words, expandGlob(t.value, t.quoted)...)
+		case tokRedirIn, tokRedirOut, tokRedirAppend:
+			p.next()
+			target := p.next()
+			if target.kind != tokWord {
+				return Command{}, fmt.Errorf("expected filename after redirection")
+			}
+			redirs = append(redirs, Redir{fd: t.fd, append: t.kind == tokRedirAppend, dupFd: -1, target: target.value})
+		case tokDup:
+			p.next()
+			redirs = append(redirs, Redir{fd: t.fd, dupFd: t.dupFd})
+		default:
+			break loop
+		}
+	}
+
+	if len(words) == 0 {
+		return Command{}, fmt.Errorf("invalid input: expected a command")
+	}
+
+	name := words[0]
+	if luaRuntime != nil {
+		if expansion, ok := luaRuntime.Alias(name); ok {
+			expanded := strings.Fields(expansion)
+			words = append(expanded, words[1:]...)
+			name = words[0]
+		}
+	}
+
+	return Command{name: name, args: words[1:], redirs: redirs}, nil
+}
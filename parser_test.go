@@ -0,0 +1,169 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestTokenizeQuotingAndEscapes(t *testing.T) {
+	cases := []struct {
+		input string
+		want  []string
+	}{
+		{`echo "hello | world"`, []string{"echo", "hello | world"}},
+		{`grep 'foo bar'`, []string{"grep", "foo bar"}},
+		{`echo \$HOME`, []string{"echo", "$HOME"}},
+	}
+	for _, c := range cases {
+		tokens, err := tokenize(c.input)
+		if err != nil {
+			t.Fatalf("tokenize(%q): %v", c.input, err)
+		}
+		var words []string
+		for _, tok := range tokens {
+			if tok.kind == tokWord {
+				words = append(words, tok.value)
+			}
+		}
+		if len(words) != len(c.want) {
+			t.Fatalf("tokenize(%q) = %v, want %v", c.input, words, c.want)
+		}
+		for i := range words {
+			if words[i] != c.want[i] {
+				t.Errorf("tokenize(%q)[%d] = %q, want %q", c.input, i, words[i], c.want[i])
+			}
+		}
+	}
+}
+
+func TestTokenizeUnterminatedQuote(t *testing.T) {
+	if _, err := tokenize(`echo "unterminated`); err == nil {
+		t.Fatal("expected an error for an unterminated double quote")
+	}
+	if _, err := tokenize(`echo 'unterminated`); err == nil {
+		t.Fatal("expected an error for an unterminated single quote")
+	}
+}
+
+func TestExpandVars(t *testing.T) {
+	os.Setenv("GOSHELL_TEST_VAR", "value")
+	defer os.Unsetenv("GOSHELL_TEST_VAR")
+
+	got := expandVars("$GOSHELL_TEST_VAR and ${GOSHELL_TEST_VAR}")
+	want := "value and value"
+	if got != want {
+		t.Errorf("expandVars() = %q, want %q", got, want)
+	}
+}
+
+func TestExpandGlobQuotedIsLiteral(t *testing.T) {
+	got := expandGlob("*.nonexistent-suffix-zzz", true)
+	if len(got) != 1 || got[0] != "*.nonexistent-suffix-zzz" {
+		t.Errorf("expandGlob(quoted) = %v, want literal pattern unchanged", got)
+	}
+}
+
+func TestExpandGlobNoMatchFallsBackToLiteral(t *testing.T) {
+	got := expandGlob("*.nonexistent-suffix-zzz", false)
+	if len(got) != 1 || got[0] != "*.nonexistent-suffix-zzz" {
+		t.Errorf("expandGlob(no match) = %v, want literal pattern unchanged", got)
+	}
+}
+
+func TestParseInputPipeline(t *testing.T) {
+	node, err := ParseInput("ls -la | grep foo")
+	if err != nil {
+		t.Fatalf("ParseInput: %v", err)
+	}
+	p, ok := node.(*Pipeline)
+	if !ok {
+		t.Fatalf("node = %T, want *Pipeline", node)
+	}
+	if len(p.cmds) != 2 {
+		t.Fatalf("len(cmds) = %d, want 2", len(p.cmds))
+	}
+	if p.cmds[0].name != "ls" || len(p.cmds[0].args) != 1 || p.cmds[0].args[0] != "-la" {
+		t.Errorf("cmds[0] = %+v", p.cmds[0])
+	}
+	if p.cmds[1].name != "grep" || len(p.cmds[1].args) != 1 || p.cmds[1].args[0] != "foo" {
+		t.Errorf("cmds[1] = %+v", p.cmds[1])
+	}
+}
+
+func TestParseInputSequence(t *testing.T) {
+	node, err := ParseInput("echo a; echo b")
+	if err != nil {
+		t.Fatalf("ParseInput: %v", err)
+	}
+	seq, ok := node.(*Sequence)
+	if !ok {
+		t.Fatalf("node = %T, want *Sequence", node)
+	}
+	if len(seq.nodes) != 2 {
+		t.Fatalf("len(nodes) = %d, want 2", len(seq.nodes))
+	}
+}
+
+func TestParseInputAndOr(t *testing.T) {
+	node, err := ParseInput("false && echo a || echo b")
+	if err != nil {
+		t.Fatalf("ParseInput: %v", err)
+	}
+	or, ok := node.(*OrList)
+	if !ok {
+		t.Fatalf("node = %T, want *OrList", node)
+	}
+	if _, ok := or.left.(*AndList); !ok {
+		t.Errorf("or.left = %T, want *AndList", or.left)
+	}
+}
+
+func TestParseInputBackground(t *testing.T) {
+	node, err := ParseInput("sleep 1 &")
+	if err != nil {
+		t.Fatalf("ParseInput: %v", err)
+	}
+	bg, ok := node.(*Background)
+	if !ok {
+		t.Fatalf("node = %T, want *Background", node)
+	}
+	if _, ok := bg.inner.(*Pipeline); !ok {
+		t.Errorf("bg.inner = %T, want *Pipeline", bg.inner)
+	}
+}
+
+func TestParseInputRedirections(t *testing.T) {
+	node, err := ParseInput("cmd > out.txt 2>&1")
+	if err != nil {
+		t.Fatalf("ParseInput: %v", err)
+	}
+	p := node.(*Pipeline)
+	cmd := p.cmds[0]
+	if len(cmd.redirs) != 2 {
+		t.Fatalf("len(redirs) = %d, want 2", len(cmd.redirs))
+	}
+	if cmd.redirs[0].fd != 1 || cmd.redirs[0].target != "out.txt" || cmd.redirs[0].append {
+		t.Errorf("redirs[0] = %+v", cmd.redirs[0])
+	}
+	if cmd.redirs[1].fd != 2 || cmd.redirs[1].dupFd != 1 {
+		t.Errorf("redirs[1] = %+v", cmd.redirs[1])
+	}
+}
+
+func TestParseInputAppendRedirection(t *testing.T) {
+	node, err := ParseInput("cmd >> out.txt")
+	if err != nil {
+		t.Fatalf("ParseInput: %v", err)
+	}
+	p := node.(*Pipeline)
+	if !p.cmds[0].redirs[0].append {
+		t.Errorf("redirs[0].append = false, want true for >>")
+	}
+}
+
+func TestParseInputBlank(t *testing.T) {
+	node, err := ParseInput("   ")
+	if err != nil || node != nil {
+		t.Errorf("ParseInput(blank) = (%v, %v), want (nil, nil)", node, err)
+	}
+}
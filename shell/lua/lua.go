@@ -0,0 +1,136 @@
+// Package lua embeds a Lua runtime that gives goshell users a real config
+// language for their prompt, aliases, and command hooks instead of
+// hardcoded Go behavior. It knows nothing about Command, pipelines, or any
+// other shell-internal type; main wires the two together.
+package lua
+
+import (
+	"fmt"
+	"os"
+
+	glua "github.com/yuin/gopher-lua"
+)
+
+// Runtime wraps a gopher-lua VM along with the alias table and hook
+// registry that the exposed prompt/alias/hook Lua bindings populate.
+type Runtime struct {
+	L       *glua.LState
+	prompt  *glua.LFunction
+	aliases map[string]string
+	hooks   map[string][]*glua.LFunction
+}
+
+// NewRuntime creates a Lua VM and registers the prompt, alias, and hook
+// bindings goshell's config file can call into.
+func NewRuntime() *Runtime {
+	r := &Runtime{
+		L:       glua.NewState(),
+		aliases: make(map[string]string),
+		hooks:   make(map[string][]*glua.LFunction),
+	}
+
+	r.L.SetGlobal("prompt", r.L.NewFunction(r.luaPrompt))
+	r.L.SetGlobal("alias", r.L.NewFunction(r.luaAlias))
+
+	hookTable := r.L.NewTable()
+	r.L.SetField(hookTable, "on", r.L.NewFunction(r.luaHookOn))
+	r.L.SetGlobal("hook", hookTable)
+
+	return r
+}
+
+// Close releases the underlying Lua VM.
+func (r *Runtime) Close() {
+	r.L.Close()
+}
+
+// LoadConfig executes path as Lua if it exists. A missing file is not an
+// error: not every user has a ~/.gocshrc.lua.
+func (r *Runtime) LoadConfig(path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	return r.L.DoFile(path)
+}
+
+// prompt(fn_or_string) overrides the shell prompt. A string is taken
+// literally; a function is called on every prompt and must return a string.
+func (r *Runtime) luaPrompt(L *glua.LState) int {
+	switch v := L.Get(1).(type) {
+	case *glua.LFunction:
+		r.prompt = v
+	case glua.LString:
+		str := v
+		r.prompt = L.NewFunction(func(L *glua.LState) int {
+			L.Push(str)
+			return 1
+		})
+	default:
+		L.ArgError(1, "prompt expects a string or a function")
+	}
+	return 0
+}
+
+// alias(name, expansion) registers a shell alias consulted by parseInput
+// before a command is dispatched.
+func (r *Runtime) luaAlias(L *glua.LState) int {
+	name := L.CheckString(1)
+	expansion := L.CheckString(2)
+	r.aliases[name] = expansion
+	return 0
+}
+
+// hook.on(event, fn) registers fn to run when event fires, one of
+// "command.pre", "command.success", or "command.fail".
+func (r *Runtime) luaHookOn(L *glua.LState) int {
+	event := L.CheckString(1)
+	fn := L.CheckFunction(2)
+	r.hooks[event] = append(r.hooks[event], fn)
+	return 0
+}
+
+// Prompt returns the current prompt string, falling back to def if no Lua
+// prompt() override has been installed or the call fails.
+func (r *Runtime) Prompt(def string) string {
+	if r.prompt == nil {
+		return def
+	}
+	if err := r.L.CallByParam(glua.P{Fn: r.prompt, NRet: 1, Protect: true}); err != nil {
+		fmt.Fprintf(os.Stderr, "lua: prompt(): %v\n", err)
+		return def
+	}
+	ret := r.L.ToString(-1)
+	r.L.Pop(1)
+	return ret
+}
+
+// Alias returns the expansion registered for name, if any.
+func (r *Runtime) Alias(name string) (string, bool) {
+	expansion, ok := r.aliases[name]
+	return expansion, ok
+}
+
+// FireHook runs every handler registered for event, passing arg as the
+// single Lua argument. Handler errors are reported but never abort the
+// shell.
+func (r *Runtime) FireHook(event string, arg string) {
+	for _, fn := range r.hooks[event] {
+		err := r.L.CallByParam(glua.P{Fn: fn, NRet: 0, Protect: true}, glua.LString(arg))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "lua: hook %q: %v\n", event, err)
+		}
+	}
+}
+
+// Eval runs code as Lua in the runtime's global scope.
+func (r *Runtime) Eval(code string) error {
+	return r.L.DoString(code)
+}
+
+// IsSyntaxError reports whether err came from code that was never valid
+// Lua, as opposed to a runtime error raised while executing valid Lua. The
+// prompt loop uses this to decide whether to fall back to shell parsing.
+func IsSyntaxError(err error) bool {
+	apiErr, ok := err.(*glua.ApiError)
+	return ok && apiErr.Type == glua.ApiErrorSyntax
+}
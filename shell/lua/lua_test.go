@@ -0,0 +1,100 @@
+package lua
+
+import "testing"
+
+func TestPromptDefaultWithNoOverride(t *testing.T) {
+	r := NewRuntime()
+	defer r.Close()
+
+	if got := r.Prompt("> "); got != "> " {
+		t.Errorf("Prompt() = %q, want default %q", got, "> ")
+	}
+}
+
+func TestPromptStringOverride(t *testing.T) {
+	r := NewRuntime()
+	defer r.Close()
+
+	if err := r.Eval(`prompt("$ ")`); err != nil {
+		t.Fatalf("Eval: %v", err)
+	}
+	if got := r.Prompt("> "); got != "$ " {
+		t.Errorf("Prompt() = %q, want %q", got, "$ ")
+	}
+}
+
+func TestPromptFunctionOverride(t *testing.T) {
+	r := NewRuntime()
+	defer r.Close()
+
+	if err := r.Eval(`prompt(function() return "fn> " end)`); err != nil {
+		t.Fatalf("Eval: %v", err)
+	}
+	if got := r.Prompt("> "); got != "fn> " {
+		t.Errorf("Prompt() = %q, want %q", got, "fn> ")
+	}
+}
+
+func TestAlias(t *testing.T) {
+	r := NewRuntime()
+	defer r.Close()
+
+	if err := r.Eval(`alias("ll", "ls -la")`); err != nil {
+		t.Fatalf("Eval: %v", err)
+	}
+	expansion, ok := r.Alias("ll")
+	if !ok || expansion != "ls -la" {
+		t.Errorf("Alias(ll) = (%q, %v), want (%q, true)", expansion, ok, "ls -la")
+	}
+	if _, ok := r.Alias("missing"); ok {
+		t.Errorf("Alias(missing) reported ok=true for an unregistered alias")
+	}
+}
+
+func TestHookFires(t *testing.T) {
+	r := NewRuntime()
+	defer r.Close()
+
+	if err := r.Eval(`
+		seen = nil
+		hook.on("command.success", function(cmd) seen = cmd end)
+	`); err != nil {
+		t.Fatalf("Eval: %v", err)
+	}
+
+	r.FireHook("command.success", "echo hi")
+
+	seen := r.L.GetGlobal("seen")
+	if seen.String() != "echo hi" {
+		t.Errorf("hook saw %q, want %q", seen.String(), "echo hi")
+	}
+}
+
+func TestHookNotFiredForOtherEvents(t *testing.T) {
+	r := NewRuntime()
+	defer r.Close()
+
+	// Firing an event with no registered handlers must not panic or error.
+	r.FireHook("command.fail", "anything")
+}
+
+func TestIsSyntaxError(t *testing.T) {
+	r := NewRuntime()
+	defer r.Close()
+
+	err := r.Eval("this is not lua (")
+	if err == nil {
+		t.Fatal("expected an error for invalid Lua syntax")
+	}
+	if !IsSyntaxError(err) {
+		t.Errorf("IsSyntaxError(%v) = false, want true", err)
+	}
+
+	runtimeErr := r.Eval(`error("boom")`)
+	if runtimeErr == nil {
+		t.Fatal("expected an error from error(\"boom\")")
+	}
+	if IsSyntaxError(runtimeErr) {
+		t.Errorf("IsSyntaxError(%v) = true, want false for a runtime error", runtimeErr)
+	}
+}